@@ -0,0 +1,109 @@
+package lazyfetcher
+
+import "context"
+
+// EvictionReason describes why an entry was removed from the cache.
+type EvictionReason int
+
+const (
+	// ReasonExpired means the entry was removed because it passed its TTL,
+	// either noticed by a Fetch or by the WithActiveExpiration goroutine.
+	ReasonExpired EvictionReason = iota
+	// ReasonCapacity means the entry was the least recently used one,
+	// evicted to stay within WithCapacity.
+	ReasonCapacity
+	// ReasonManualDelete means the entry was removed by Delete or Clear.
+	ReasonManualDelete
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonManualDelete:
+		return "manual_delete"
+	default:
+		return "unknown"
+	}
+}
+
+type insertionCallback[T any] func(ctx context.Context, key string, values []T, priority int)
+
+type evictionCallback func(key string, reason EvictionReason)
+
+type refreshCallback[T any] func(key string, oldValues, newValues []T)
+
+// OnInsertion registers a callback invoked whenever a key absent from the
+// cache is fetched and stored for the first time. Multiple subscribers are
+// supported; callbacks run on lf's bounded event dispatcher so a slow
+// subscriber can't stall Fetch.
+func (lf *LazyFetcher[T]) OnInsertion(cb func(ctx context.Context, key string, values []T, priority int)) {
+	lf.callbacksMu.Lock()
+	defer lf.callbacksMu.Unlock()
+	lf.onInsertion = append(lf.onInsertion, cb)
+}
+
+// OnEviction registers a callback invoked whenever an entry is removed from
+// the cache, along with why. Multiple subscribers are supported; callbacks
+// run on lf's bounded event dispatcher so a slow subscriber can't stall
+// Fetch.
+func (lf *LazyFetcher[T]) OnEviction(cb func(key string, reason EvictionReason)) {
+	lf.callbacksMu.Lock()
+	defer lf.callbacksMu.Unlock()
+	lf.onEviction = append(lf.onEviction, cb)
+}
+
+// OnRefresh registers a callback invoked whenever an existing key's value is
+// replaced by a new fetch, whether from a blocking Fetch past decayEvery or
+// a WithBackgroundRefresh background refresh. Multiple subscribers are
+// supported; callbacks run on lf's bounded event dispatcher so a slow
+// subscriber can't stall Fetch.
+func (lf *LazyFetcher[T]) OnRefresh(cb func(key string, oldValues, newValues []T)) {
+	lf.callbacksMu.Lock()
+	defer lf.callbacksMu.Unlock()
+	lf.onRefresh = append(lf.onRefresh, cb)
+}
+
+func (lf *LazyFetcher[T]) emitInsertion(ctx context.Context, key string, values []T, priority int) {
+	lf.callbacksMu.RLock()
+	subs := lf.onInsertion
+	lf.callbacksMu.RUnlock()
+	if len(subs) == 0 {
+		return
+	}
+	lf.events.dispatch(func() {
+		for _, cb := range subs {
+			cb(ctx, key, values, priority)
+		}
+	})
+}
+
+func (lf *LazyFetcher[T]) emitEviction(key string, reason EvictionReason) {
+	lf.callbacksMu.RLock()
+	subs := lf.onEviction
+	lf.callbacksMu.RUnlock()
+	if len(subs) == 0 {
+		return
+	}
+	lf.events.dispatch(func() {
+		for _, cb := range subs {
+			cb(key, reason)
+		}
+	})
+}
+
+func (lf *LazyFetcher[T]) emitRefresh(key string, oldValues, newValues []T) {
+	lf.callbacksMu.RLock()
+	subs := lf.onRefresh
+	lf.callbacksMu.RUnlock()
+	if len(subs) == 0 {
+		return
+	}
+	lf.events.dispatch(func() {
+		for _, cb := range subs {
+			cb(key, oldValues, newValues)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package lazyfetcher
+
+import "sync"
+
+const (
+	// eventWorkers is how many goroutines drain the event queue, bounding
+	// callback concurrency regardless of how many events are fired.
+	eventWorkers = 4
+	// eventQueueSize bounds how many pending callback invocations can queue
+	// up before new events are dropped rather than blocking the caller.
+	eventQueueSize = 256
+)
+
+// eventDispatcher runs a small, bounded pool of goroutines that invoke
+// OnInsertion/OnEviction/OnRefresh callbacks, so a burst of events (e.g.
+// mass eviction under WithCapacity) can't spawn unbounded goroutines, and a
+// slow subscriber can't stall Fetch. The pool starts lazily, on the first
+// dispatched event, and is torn down by stop. The zero value is ready to
+// use.
+type eventDispatcher struct {
+	mu      sync.Mutex
+	queue   chan func()
+	stopped bool
+}
+
+// dispatch queues job to run on the worker pool, starting it if this is the
+// first event. If the queue is full, job is dropped rather than blocking
+// the caller. The send happens under d.mu so it can't race with stop()
+// closing the queue out from under it.
+func (d *eventDispatcher) dispatch(job func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	if d.queue == nil {
+		d.queue = make(chan func(), eventQueueSize)
+		for i := 0; i < eventWorkers; i++ {
+			go d.work()
+		}
+	}
+
+	select {
+	case d.queue <- job:
+	default:
+		// Queue is full; drop the event rather than block the caller.
+	}
+}
+
+func (d *eventDispatcher) work() {
+	for job := range d.queue {
+		job()
+	}
+}
+
+// stop shuts down the worker pool, if one was ever started. Safe to call
+// even if no event was ever dispatched.
+func (d *eventDispatcher) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	if d.queue != nil {
+		close(d.queue)
+	}
+}
@@ -1,17 +1,32 @@
 package lazyfetcher
 
 import (
+	"container/list"
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/errors/v5"
+	"golang.org/x/sync/singleflight"
 )
 
 type decayablePriorityList[T any] struct {
 	values             []T
 	highestPriorityIdx int
 	lastFetchedAt      time.Time
+	// refreshing is set while a background refresh (see
+	// WithBackgroundRefresh) is in flight for this entry, so a burst of
+	// callers serving the stale value only spawns one refresh.
+	refreshing atomic.Bool
+}
+
+// lruNode is what's stored in order, the LRU linked list: the key is kept
+// alongside the entry so that evicting the back of the list can look up
+// (and remove) the right map entry.
+type lruNode[T any] struct {
+	key   string
+	entry *decayablePriorityList[T]
 }
 
 // LazyFetcher allows inserting and fetching a map[string]T
@@ -21,22 +36,127 @@ type decayablePriorityList[T any] struct {
 //  2. If the key exists, but has expired, calls fetcher(key) and stores the
 //     result in the map, then return it and refresh the expiration time
 //  3. If the key exists and has not expired, return the value in the map
+//
+// If WithBackgroundRefresh is set, step 2 changes: a soft-expired entry
+// (older than decayEvery but not yet older than staleFor) is returned as-is
+// while a refresh happens in the background, so callers only block once the
+// entry is older than staleFor.
+//
+// Concurrent Fetch calls for the same key are coalesced: only one of them
+// actually invokes fetcher, and the rest block on the shared result.
+//
+// Storage is a map + LRU linked list protected by mu, so that a capacity
+// (see WithCapacity) can be enforced by evicting the least recently used
+// entry. If WithActiveExpiration is set, a background goroutine also evicts
+// entries as soon as they expire, using an expiry min-heap to know which key
+// is due next without scanning the whole map.
+//
+// OnInsertion, OnEviction, and OnRefresh let callers observe what the cache
+// is doing without wrapping fetcher.
 type LazyFetcher[T any] struct {
 	fetcher func(context.Context, string) ([]T, int, error)
-	entries sync.Map
-	// entries    map[string]*entry[T]
+	group   singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // list.Element.Value is *lruNode[T]; front = most recently used
+
 	decayEvery time.Duration
+	capacity   int // 0 means unbounded
+
+	backgroundRefresh bool
+	staleFor          time.Duration
+
+	activeExpiration bool
+	expiry           ttlHeap
+	expiryIdx        map[string]*ttlItem
+	wakeCh           chan struct{}
+	stopCh           chan struct{}
+	doneCh           chan struct{}
+
+	callbacksMu sync.RWMutex
+	onInsertion []insertionCallback[T]
+	onEviction  []evictionCallback
+	onRefresh   []refreshCallback[T]
+	events      eventDispatcher
+
+	metrics metricsCounters
+}
+
+// Option configures optional behavior on a LazyFetcher created via New.
+type Option[T any] func(*LazyFetcher[T])
+
+// WithCapacity bounds the number of distinct keys LazyFetcher holds at once.
+// Once the bound is reached, inserting a new key evicts the least recently
+// used one. A capacity of 0 (the default) means unbounded.
+func WithCapacity[T any](capacity int) Option[T] {
+	return func(lf *LazyFetcher[T]) {
+		lf.capacity = capacity
+	}
+}
+
+// WithBackgroundRefresh enables stale-while-revalidate: once an entry is
+// older than decayEvery (the soft expiry), Fetch returns the stale cached
+// value immediately and kicks off a single background refresh, instead of
+// blocking the caller on fetcher. Callers only block once an entry is older
+// than staleFor, the hard expiry.
+func WithBackgroundRefresh[T any](staleFor time.Duration) Option[T] {
+	return func(lf *LazyFetcher[T]) {
+		lf.backgroundRefresh = true
+		lf.staleFor = staleFor
+	}
+}
+
+// WithActiveExpiration starts a background goroutine that proactively evicts
+// entries as soon as they expire, instead of waiting for a Fetch to notice.
+// Call Stop to shut the goroutine down.
+func WithActiveExpiration[T any](enabled bool) Option[T] {
+	return func(lf *LazyFetcher[T]) {
+		lf.activeExpiration = enabled
+	}
 }
 
 func New[T any](
 	decayEvery time.Duration,
 	fetcher func(context.Context, string) ([]T, int, error),
+	opts ...Option[T],
 ) *LazyFetcher[T] {
-	return &LazyFetcher[T]{
+	lf := &LazyFetcher[T]{
 		fetcher:    fetcher,
-		entries:    sync.Map{},
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
 		decayEvery: decayEvery,
+		expiryIdx:  make(map[string]*ttlItem),
+		wakeCh:     make(chan struct{}, 1),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(lf)
+	}
+	if lf.activeExpiration {
+		go lf.runExpirationLoop()
 	}
+	return lf
+}
+
+// Stop shuts down the background expiration goroutine started by
+// WithActiveExpiration, if any, and the worker pool dispatching
+// OnInsertion/OnEviction/OnRefresh callbacks, if one was ever started. Not
+// safe to call concurrently with itself.
+func (lf *LazyFetcher[T]) Stop() {
+	if lf.activeExpiration {
+		close(lf.stopCh)
+		<-lf.doneCh
+	}
+	lf.events.stop()
+}
+
+// fetchResult is what's shared between callers coalesced onto the same
+// singleflight call.
+type fetchResult[T any] struct {
+	values             []T
+	highestPriorityIdx int
 }
 
 func (lf *LazyFetcher[T]) FetchPriority(
@@ -45,10 +165,16 @@ func (lf *LazyFetcher[T]) FetchPriority(
 ) (T, error) {
 	ls, activeIdx, err := lf.Fetch(ctx, key)
 	if err != nil {
-		// Because Go doesn't have a nullable constraint
-		// https://github.com/golang/go/issues/53656
-		var null T
-		return null, err
+		if activeIdx < 0 || activeIdx >= len(ls) {
+			// Because Go doesn't have a nullable constraint
+			// https://github.com/golang/go/issues/53656
+			var null T
+			return null, err
+		}
+		// Fetch may still return a stale value alongside the error, e.g. a
+		// failed refresh of an already-cached key; pass it through instead
+		// of discarding it.
+		return ls[activeIdx], err
 	}
 	return ls[activeIdx], nil
 }
@@ -58,38 +184,186 @@ func (lf *LazyFetcher[T]) Fetch(
 	key string,
 ) ([]T, int, error) {
 	// Check if the key exists in the map
-	if entry, ok := lf.entries.Load(key); ok {
-		typedEntry := entry.(*decayablePriorityList[T])
+	if entry, ok := lf.load(key); ok {
+		age := time.Since(entry.lastFetchedAt)
+
+		// With background refresh enabled, a soft-expired entry is served
+		// stale while a refresh happens behind the scenes; callers only
+		// block once the entry is stale long enough to hit staleFor. This
+		// isn't counted as a Hit: the entry is expired, it's just served
+		// stale on purpose, and the background refresh (if one actually
+		// runs) already counts as a Refresh once it lands.
+		if lf.backgroundRefresh && age > lf.decayEvery && age <= lf.staleFor {
+			lf.refreshAsync(key, entry)
+			return entry.values, entry.highestPriorityIdx, nil
+		}
+
 		// Check if the entry has expired
-		if time.Since(typedEntry.lastFetchedAt) > lf.decayEvery {
-			// If the entry has expired, fetch a new value and update the entry
-			newValues, newHighestPriorityIdx, err := lf.fetcher(ctx, key)
+		if age > lf.decayEvery {
+			// If the entry has expired, fetch a new value and update the entry.
+			// Coalesce concurrent refreshes of the same key into a single
+			// fetcher call.
+			newValues, newHighestPriorityIdx, err := lf.fetchAndStore(ctx, key)
 			if err != nil {
-				return nil, typedEntry.highestPriorityIdx, errors.Wrapf(
-					err,
-					"fetching %s",
-					key,
-				)
+				lf.metrics.fetchErrors.Add(1)
+				// Keep serving the stale entry on a failed refresh rather
+				// than discarding it from the caller's perspective; it's
+				// still sitting in lf.entries either way.
+				return entry.values, entry.highestPriorityIdx, err
 			}
-			typedEntry.values = newValues
-			typedEntry.highestPriorityIdx = newHighestPriorityIdx
-			typedEntry.lastFetchedAt = time.Now()
-			lf.entries.Store(key, typedEntry)
 			return newValues, newHighestPriorityIdx, nil
 		}
 		// If the entry has not expired, return the value
-		return typedEntry.values, typedEntry.highestPriorityIdx, nil
+		lf.metrics.hits.Add(1)
+		return entry.values, entry.highestPriorityIdx, nil
 	}
 
 	// If the key does not exist in the map, fetch a new value and add it to the map
-	newValues, newHighestPriorityIdx, err := lf.fetcher(ctx, key)
+	lf.metrics.misses.Add(1)
+	newValues, newHighestPriorityIdx, err := lf.fetchAndStore(ctx, key)
 	if err != nil {
-		return nil, 0, errors.Wrapf(err, "fetching %s", key)
+		lf.metrics.fetchErrors.Add(1)
+		return nil, 0, err
 	}
-	lf.entries.Store(key, &decayablePriorityList[T]{
-		values:             newValues,
-		highestPriorityIdx: newHighestPriorityIdx,
-		lastFetchedAt:      time.Now(),
-	})
 	return newValues, newHighestPriorityIdx, nil
 }
+
+// peekLocked looks up key without affecting LRU recency. Callers must hold
+// lf.mu.
+func (lf *LazyFetcher[T]) peekLocked(key string) (*decayablePriorityList[T], bool) {
+	elem, ok := lf.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*lruNode[T]).entry, true
+}
+
+// load looks up key and, if present, marks it as most recently used.
+func (lf *LazyFetcher[T]) load(key string) (*decayablePriorityList[T], bool) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	elem, ok := lf.entries[key]
+	if !ok {
+		return nil, false
+	}
+	lf.order.MoveToFront(elem)
+	return elem.Value.(*lruNode[T]).entry, true
+}
+
+// fetchAndStore calls lf.fetcher for key, coalescing concurrent callers onto
+// a single in-flight call via singleflight, and stores the result on
+// success. Storing (and firing OnInsertion/OnRefresh) happens inside the
+// singleflight call itself, so it runs exactly once per actual fetch rather
+// than once per coalesced caller.
+//
+// The fetcher itself always runs with a detached context (context.Background)
+// so that one caller cancelling its ctx doesn't cancel the fetch for every
+// other caller waiting on the same key; each caller still honors its own ctx
+// while waiting for the shared result to come back.
+func (lf *LazyFetcher[T]) fetchAndStore(
+	ctx context.Context,
+	key string,
+) ([]T, int, error) {
+	resChan := lf.group.DoChan(key, func() (interface{}, error) {
+		values, highestPriorityIdx, err := lf.fetcher(context.Background(), key)
+		if err != nil {
+			return nil, err
+		}
+
+		lf.mu.Lock()
+		oldEntry, existed := lf.peekLocked(key)
+		lf.storeLocked(key, &decayablePriorityList[T]{
+			values:             values,
+			highestPriorityIdx: highestPriorityIdx,
+			lastFetchedAt:      time.Now(),
+		})
+		lf.mu.Unlock()
+
+		if existed {
+			lf.metrics.refreshes.Add(1)
+			lf.emitRefresh(key, oldEntry.values, values)
+		} else {
+			lf.metrics.insertions.Add(1)
+			lf.emitInsertion(ctx, key, values, highestPriorityIdx)
+		}
+
+		return fetchResult[T]{values: values, highestPriorityIdx: highestPriorityIdx}, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	case res := <-resChan:
+		if res.Err != nil {
+			return nil, 0, errors.Wrapf(res.Err, "fetching %s", key)
+		}
+		result := res.Val.(fetchResult[T])
+		return result.values, result.highestPriorityIdx, nil
+	}
+}
+
+// refreshAsync kicks off a single background refresh of key, if one isn't
+// already in flight for entry, so a burst of callers serving the same stale
+// entry doesn't spawn a refresh each. The refresh itself goes through
+// fetchAndStore, so it's gated by the same singleflight group as a blocking
+// Fetch for key, and keeps the stale entry in place on error.
+func (lf *LazyFetcher[T]) refreshAsync(key string, entry *decayablePriorityList[T]) {
+	if !entry.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer entry.refreshing.Store(false)
+		_, _, _ = lf.fetchAndStore(context.Background(), key)
+	}()
+}
+
+// storeLocked inserts or updates key's entry, refreshing LRU recency and the
+// expiration heap, and evicts the least recently used entry if that pushes
+// the cache over capacity. Callers must hold lf.mu.
+func (lf *LazyFetcher[T]) storeLocked(key string, entry *decayablePriorityList[T]) {
+	if elem, ok := lf.entries[key]; ok {
+		elem.Value.(*lruNode[T]).entry = entry
+		lf.order.MoveToFront(elem)
+	} else {
+		elem := lf.order.PushFront(&lruNode[T]{key: key, entry: entry})
+		lf.entries[key] = elem
+	}
+	// With background refresh enabled, an entry isn't actually dead until
+	// it passes staleFor (the hard expiry) — decayEvery only marks when it
+	// goes stale and starts serving from the background-refresh path.
+	// Scheduling active expiration off decayEvery would reap it out from
+	// under that path before it ever gets to serve stale.
+	expiresAfter := lf.decayEvery
+	if lf.backgroundRefresh {
+		expiresAfter = lf.staleFor
+	}
+	lf.updateExpiryLocked(key, entry.lastFetchedAt.Add(expiresAfter))
+
+	if lf.capacity > 0 && len(lf.entries) > lf.capacity {
+		lf.evictLRULocked()
+	}
+}
+
+// evictLRULocked drops the least recently used entry. Callers must hold lf.mu.
+func (lf *LazyFetcher[T]) evictLRULocked() {
+	elem := lf.order.Back()
+	if elem == nil {
+		return
+	}
+	lf.deleteLocked(elem.Value.(*lruNode[T]).key, ReasonCapacity)
+}
+
+// deleteLocked removes key from both the LRU list and the expiration heap,
+// and fires OnEviction with reason if key was present. Callers must hold
+// lf.mu.
+func (lf *LazyFetcher[T]) deleteLocked(key string, reason EvictionReason) {
+	elem, ok := lf.entries[key]
+	if !ok {
+		return
+	}
+	lf.order.Remove(elem)
+	delete(lf.entries, key)
+	lf.removeExpiryLocked(key)
+	lf.metrics.evictions.Add(1)
+	lf.emitEviction(key, reason)
+}
@@ -3,6 +3,8 @@ package lazyfetcher
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -141,6 +143,30 @@ func TestLazyFetcher(t *testing.T) {
 		require.Equal(t, 2, fetchCount)
 	})
 
+	t.Run("FetchPriority with error on expired key returns the stale value", func(t *testing.T) {
+		fetchCount := 0
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			fetchCount++
+			if fetchCount == 1 {
+				return []string{"initial value"}, 0, nil
+			}
+			return nil, 0, errors.New("fetch error")
+		}
+
+		lf := New[string](time.Millisecond, fetcher)
+
+		value1, err := lf.FetchPriority(context.Background(), "key1")
+		require.NoError(t, err)
+		require.Equal(t, "initial value", value1)
+
+		time.Sleep(2 * time.Millisecond)
+
+		value2, err := lf.FetchPriority(context.Background(), "key1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "fetch error")
+		require.Equal(t, "initial value", value2)
+	})
+
 	t.Run("Multiple fetches yield non-expired value", func(t *testing.T) {
 		fetchCount := 0
 		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
@@ -159,4 +185,450 @@ func TestLazyFetcher(t *testing.T) {
 			require.Equal(t, 0, p)
 		}
 	})
+
+	t.Run("Concurrent fetches for the same key are coalesced", func(t *testing.T) {
+		var fetchCount int64
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			atomic.AddInt64(&fetchCount, 1)
+			time.Sleep(20 * time.Millisecond) // Simulate slow fetch
+			return []string{"value"}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher)
+
+		const numGoroutines = 100
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func() {
+				defer wg.Done()
+				values, priority, err := lf.Fetch(context.Background(), "key1")
+				require.NoError(t, err)
+				require.Len(t, values, 1)
+				require.Contains(t, values, "value")
+				require.Equal(t, 0, priority)
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, int64(1), atomic.LoadInt64(&fetchCount))
+	})
+
+	t.Run("Caller ctx cancellation does not cancel the shared fetch", func(t *testing.T) {
+		var fetchCount int64
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			atomic.AddInt64(&fetchCount, 1)
+			time.Sleep(20 * time.Millisecond)
+			return []string{"value"}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := lf.Fetch(ctx, "key1")
+			require.ErrorIs(t, err, context.Canceled)
+		}()
+
+		// Give the cancelled caller time to join the in-flight fetch, then
+		// cancel it. The fetch itself should still complete and populate
+		// the cache for everyone else.
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+		wg.Wait()
+
+		values, priority, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+		require.Contains(t, values, "value")
+		require.Equal(t, 0, priority)
+		require.Equal(t, int64(1), atomic.LoadInt64(&fetchCount))
+	})
+
+	t.Run("WithCapacity evicts the least recently used key", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher, WithCapacity[string](2))
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		_, _, err = lf.Fetch(context.Background(), "key2")
+		require.NoError(t, err)
+
+		// Touch key1 so key2 becomes the least recently used.
+		_, _, err = lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		_, _, err = lf.Fetch(context.Background(), "key3")
+		require.NoError(t, err)
+
+		require.True(t, lf.Has("key1"))
+		require.False(t, lf.Has("key2"))
+		require.True(t, lf.Has("key3"))
+	})
+
+	t.Run("WithActiveExpiration evicts entries without a Fetch", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](5*time.Millisecond, fetcher, WithActiveExpiration[string](true))
+		defer lf.Stop()
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		require.True(t, lf.Has("key1"))
+
+		require.Eventually(t, func() bool {
+			return !lf.Has("key1")
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Stop shuts down the active expiration goroutine", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher, WithActiveExpiration[string](true))
+		lf.Stop()
+
+		select {
+		case <-lf.doneCh:
+		case <-time.After(time.Second):
+			t.Fatal("expiration goroutine did not shut down")
+		}
+	})
+
+	t.Run("WithBackgroundRefresh serves stale values without blocking", func(t *testing.T) {
+		var fetchCount int64
+		block := make(chan struct{})
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			n := atomic.AddInt64(&fetchCount, 1)
+			if n == 2 {
+				<-block // Hold the background refresh open.
+			}
+			return []string{fmt.Sprintf("value%d", n)}, 0, nil
+		}
+
+		lf := New[string](
+			5*time.Millisecond,
+			fetcher,
+			WithBackgroundRefresh[string](time.Minute),
+		)
+
+		values1, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		require.Contains(t, values1, "value1")
+
+		time.Sleep(10 * time.Millisecond) // Soft-expire, but well within staleFor.
+
+		values2, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		require.Contains(t, values2, "value1", "a soft-expired Fetch should not block on the refresh")
+
+		close(block)
+
+		require.Eventually(t, func() bool {
+			values, _, err := lf.Fetch(context.Background(), "key1")
+			return err == nil && len(values) == 1 && values[0] == "value2"
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("WithBackgroundRefresh blocks once an entry is older than staleFor", func(t *testing.T) {
+		fetchCount := 0
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			fetchCount++
+			return []string{fmt.Sprintf("value%d", fetchCount)}, 0, nil
+		}
+
+		lf := New[string](
+			time.Millisecond,
+			fetcher,
+			WithBackgroundRefresh[string](2*time.Millisecond),
+		)
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond) // Past both decayEvery and staleFor.
+
+		values, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		require.Contains(t, values, "value2")
+	})
+
+	t.Run("WithActiveExpiration schedules off staleFor, not decayEvery, when combined with WithBackgroundRefresh", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](
+			10*time.Millisecond,
+			fetcher,
+			WithBackgroundRefresh[string](200*time.Millisecond),
+			WithActiveExpiration[string](true),
+		)
+		defer lf.Stop()
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		// Past decayEvery (the soft expiry): active expiration must not
+		// have reaped the entry, since background refresh is supposed to
+		// still be serving it stale.
+		time.Sleep(50 * time.Millisecond)
+		require.True(t, lf.Has("key1"))
+
+		// Past staleFor (the hard expiry): now active expiration should
+		// reap it.
+		require.Eventually(t, func() bool {
+			return !lf.Has("key1")
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("OnInsertion fires once for a new key", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{"value"}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher)
+
+		var calls int64
+		lf.OnInsertion(func(ctx context.Context, key string, values []string, priority int) {
+			atomic.AddInt64(&calls, 1)
+			require.Equal(t, "key1", key)
+			require.Contains(t, values, "value")
+		})
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		// Cache hit: should not fire OnInsertion again.
+		_, _, err = lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt64(&calls) == 1
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("OnRefresh fires with old and new values", func(t *testing.T) {
+		fetchCount := 0
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			fetchCount++
+			return []string{fmt.Sprintf("value%d", fetchCount)}, 0, nil
+		}
+
+		lf := New[string](time.Millisecond, fetcher)
+
+		var oldSeen, newSeen []string
+		done := make(chan struct{})
+		lf.OnRefresh(func(key string, oldValues, newValues []string) {
+			oldSeen, newSeen = oldValues, newValues
+			close(done)
+		})
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+		_, _, err = lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("OnRefresh did not fire")
+		}
+		require.Contains(t, oldSeen, "value1")
+		require.Contains(t, newSeen, "value2")
+	})
+
+	t.Run("OnEviction fires with the capacity reason", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher, WithCapacity[string](1))
+
+		var evictedKey string
+		var evictedReason EvictionReason
+		done := make(chan struct{})
+		lf.OnEviction(func(key string, reason EvictionReason) {
+			evictedKey, evictedReason = key, reason
+			close(done)
+		})
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		_, _, err = lf.Fetch(context.Background(), "key2")
+		require.NoError(t, err)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("OnEviction did not fire")
+		}
+		require.Equal(t, "key1", evictedKey)
+		require.Equal(t, ReasonCapacity, evictedReason)
+	})
+
+	t.Run("Peek returns the cached value without fetching or touching recency", func(t *testing.T) {
+		fetchCount := 0
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			fetchCount++
+			return []string{fmt.Sprintf("value%d", fetchCount)}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher)
+
+		_, _, ok := lf.Peek("key1")
+		require.False(t, ok)
+		require.Equal(t, 0, fetchCount)
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		values, priority, ok := lf.Peek("key1")
+		require.True(t, ok)
+		require.Contains(t, values, "value1")
+		require.Equal(t, 0, priority)
+		require.Equal(t, 1, fetchCount)
+	})
+
+	t.Run("Delete removes a key and fires OnEviction with the manual reason", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher)
+
+		var evictedReason EvictionReason
+		done := make(chan struct{})
+		lf.OnEviction(func(key string, reason EvictionReason) {
+			evictedReason = reason
+			close(done)
+		})
+
+		require.False(t, lf.Delete("key1"))
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		require.True(t, lf.Delete("key1"))
+		require.False(t, lf.Has("key1"))
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("OnEviction did not fire")
+		}
+		require.Equal(t, ReasonManualDelete, evictedReason)
+	})
+
+	t.Run("Clear, Len, and Keys manage the whole cache", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](time.Minute, fetcher)
+
+		require.Equal(t, 0, lf.Len())
+		require.Empty(t, lf.Keys())
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+		_, _, err = lf.Fetch(context.Background(), "key2")
+		require.NoError(t, err)
+
+		require.Equal(t, 2, lf.Len())
+		require.ElementsMatch(t, []string{"key1", "key2"}, lf.Keys())
+
+		lf.Clear()
+
+		require.Equal(t, 0, lf.Len())
+		require.Empty(t, lf.Keys())
+		require.False(t, lf.Has("key1"))
+		require.False(t, lf.Has("key2"))
+	})
+
+	t.Run("Metrics counts hits, misses, refreshes, errors, and evictions", func(t *testing.T) {
+		fail := false
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			if fail {
+				return nil, 0, errors.New("fetch error")
+			}
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](time.Millisecond, fetcher, WithCapacity[string](1))
+
+		// Miss + insertion for key1.
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		// Hit.
+		_, _, err = lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		time.Sleep(2 * time.Millisecond)
+
+		// Refresh (key1 expired).
+		_, _, err = lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		// Miss + insertion for key2, which evicts key1 (capacity 1).
+		_, _, err = lf.Fetch(context.Background(), "key2")
+		require.NoError(t, err)
+
+		time.Sleep(2 * time.Millisecond)
+		fail = true
+		_, _, err = lf.Fetch(context.Background(), "key2")
+		require.Error(t, err)
+
+		require.Eventually(t, func() bool {
+			m := lf.Metrics()
+			return m.Insertions == 2 && m.Evictions == 1
+		}, time.Second, time.Millisecond)
+
+		m := lf.Metrics()
+		require.Equal(t, uint64(2), m.Misses)
+		require.Equal(t, uint64(1), m.Hits)
+		require.Equal(t, uint64(1), m.Refreshes)
+		require.Equal(t, uint64(1), m.FetchErrors)
+		require.Equal(t, uint64(2), m.Insertions)
+		require.Equal(t, uint64(1), m.Evictions)
+
+		lf.MetricsReset()
+		require.Equal(t, Metrics{}, lf.Metrics())
+	})
+
+	t.Run("Metrics does not double-count a soft-expiry stale-serve as both a Hit and a Refresh", func(t *testing.T) {
+		fetcher := func(ctx context.Context, key string) ([]string, int, error) {
+			return []string{key}, 0, nil
+		}
+
+		lf := New[string](10*time.Millisecond, fetcher, WithBackgroundRefresh[string](time.Hour))
+
+		_, _, err := lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+
+		// Soft-expired: served stale, background refresh kicked off.
+		_, _, err = lf.Fetch(context.Background(), "key1")
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			return lf.Metrics().Refreshes == 1
+		}, time.Second, time.Millisecond)
+
+		m := lf.Metrics()
+		require.Equal(t, uint64(1), m.Misses)
+		require.Equal(t, uint64(0), m.Hits)
+		require.Equal(t, uint64(1), m.Refreshes)
+	})
 }
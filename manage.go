@@ -0,0 +1,67 @@
+package lazyfetcher
+
+// Has reports whether key is currently cached, without affecting LRU
+// recency.
+func (lf *LazyFetcher[T]) Has(key string) bool {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	_, ok := lf.entries[key]
+	return ok
+}
+
+// Len returns the number of keys currently cached.
+func (lf *LazyFetcher[T]) Len() int {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	return len(lf.entries)
+}
+
+// Keys returns a snapshot of all keys currently cached, in no particular
+// order.
+func (lf *LazyFetcher[T]) Keys() []string {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	keys := make([]string, 0, len(lf.entries))
+	for key := range lf.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Peek returns key's cached values and priority without triggering a fetch
+// or updating LRU recency. The bool reports whether key was present.
+func (lf *LazyFetcher[T]) Peek(key string) ([]T, int, bool) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	entry, ok := lf.peekLocked(key)
+	if !ok {
+		return nil, 0, false
+	}
+	return entry.values, entry.highestPriorityIdx, true
+}
+
+// Delete removes key from the cache, firing OnEviction with
+// ReasonManualDelete if it was present. Returns whether key was present.
+func (lf *LazyFetcher[T]) Delete(key string) bool {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	_, ok := lf.entries[key]
+	if ok {
+		lf.deleteLocked(key, ReasonManualDelete)
+	}
+	return ok
+}
+
+// Clear removes every entry from the cache, firing OnEviction with
+// ReasonManualDelete for each.
+func (lf *LazyFetcher[T]) Clear() {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	keys := make([]string, 0, len(lf.entries))
+	for key := range lf.entries {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		lf.deleteLocked(key, ReasonManualDelete)
+	}
+}
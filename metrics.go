@@ -0,0 +1,49 @@
+package lazyfetcher
+
+import "sync/atomic"
+
+// Metrics is a point-in-time snapshot of a LazyFetcher's counters, taken via
+// (*LazyFetcher).Metrics.
+type Metrics struct {
+	Hits        uint64
+	Misses      uint64
+	Refreshes   uint64
+	FetchErrors uint64
+	Evictions   uint64
+	Insertions  uint64
+}
+
+// metricsCounters holds the live atomic counters backing Metrics. Embedded
+// by value in LazyFetcher so every counter lives inline, not behind a
+// pointer or a lock.
+type metricsCounters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	refreshes   atomic.Uint64
+	fetchErrors atomic.Uint64
+	evictions   atomic.Uint64
+	insertions  atomic.Uint64
+}
+
+// Metrics returns a snapshot of the cache's hit/miss/refresh/error/eviction/
+// insertion counters since construction (or the last MetricsReset).
+func (lf *LazyFetcher[T]) Metrics() Metrics {
+	return Metrics{
+		Hits:        lf.metrics.hits.Load(),
+		Misses:      lf.metrics.misses.Load(),
+		Refreshes:   lf.metrics.refreshes.Load(),
+		FetchErrors: lf.metrics.fetchErrors.Load(),
+		Evictions:   lf.metrics.evictions.Load(),
+		Insertions:  lf.metrics.insertions.Load(),
+	}
+}
+
+// MetricsReset zeroes every counter. Intended for test isolation.
+func (lf *LazyFetcher[T]) MetricsReset() {
+	lf.metrics.hits.Store(0)
+	lf.metrics.misses.Store(0)
+	lf.metrics.refreshes.Store(0)
+	lf.metrics.fetchErrors.Store(0)
+	lf.metrics.evictions.Store(0)
+	lf.metrics.insertions.Store(0)
+}
@@ -0,0 +1,144 @@
+package lazyfetcher
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ttlItem is a single entry in the expiration heap: the key that expires at
+// expiresAt. index is maintained by container/heap so a later Fix or Remove
+// for the same key can locate it in O(log n) instead of scanning the heap.
+type ttlItem struct {
+	key       string
+	expiresAt time.Time
+	index     int
+}
+
+// ttlHeap is a min-heap of ttlItem ordered by expiresAt, so the next key due
+// to expire is always at the root.
+type ttlHeap []*ttlItem
+
+func (h ttlHeap) Len() int { return len(h) }
+
+func (h ttlHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+func (h ttlHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ttlHeap) Push(x any) {
+	item := x.(*ttlItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *ttlHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// updateExpiryLocked records (or moves) key's expiration to expiresAt in the
+// heap, and wakes the background expiration goroutine so it can re-evaluate
+// what's due next. A no-op when active expiration isn't enabled. Callers
+// must hold lf.mu.
+func (lf *LazyFetcher[T]) updateExpiryLocked(key string, expiresAt time.Time) {
+	if !lf.activeExpiration {
+		return
+	}
+	if item, ok := lf.expiryIdx[key]; ok {
+		item.expiresAt = expiresAt
+		heap.Fix(&lf.expiry, item.index)
+	} else {
+		item := &ttlItem{key: key, expiresAt: expiresAt}
+		heap.Push(&lf.expiry, item)
+		lf.expiryIdx[key] = item
+	}
+	lf.wake()
+}
+
+// removeExpiryLocked drops key from the expiration heap, if present. A
+// no-op when active expiration isn't enabled. Callers must hold lf.mu.
+func (lf *LazyFetcher[T]) removeExpiryLocked(key string) {
+	if !lf.activeExpiration {
+		return
+	}
+	item, ok := lf.expiryIdx[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&lf.expiry, item.index)
+	delete(lf.expiryIdx, key)
+}
+
+// wake signals the background expiration goroutine to recompute how long it
+// should sleep, without blocking if it's already been signalled.
+func (lf *LazyFetcher[T]) wake() {
+	select {
+	case lf.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// runExpirationLoop is the background goroutine started by
+// WithActiveExpiration. It sleeps until the next entry in the heap is due,
+// evicts it, and repeats; it wakes early whenever storeLocked inserts or
+// refreshes an entry that might change what's due next, and exits once Stop
+// closes lf.stopCh.
+func (lf *LazyFetcher[T]) runExpirationLoop() {
+	defer close(lf.doneCh)
+
+	const idleWait = time.Hour
+	timer := time.NewTimer(idleWait)
+	defer timer.Stop()
+
+	for {
+		lf.mu.Lock()
+		wait := idleWait
+		if len(lf.expiry) > 0 {
+			wait = time.Until(lf.expiry[0].expiresAt)
+		}
+		lf.mu.Unlock()
+
+		if wait <= 0 {
+			lf.evictExpired()
+			continue
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-lf.stopCh:
+			return
+		case <-lf.wakeCh:
+		case <-timer.C:
+		}
+	}
+}
+
+// evictExpired removes the heap root if it's actually due, re-checking
+// under the lock since time may have passed since runExpirationLoop last
+// looked.
+func (lf *LazyFetcher[T]) evictExpired() {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	if len(lf.expiry) == 0 {
+		return
+	}
+	root := lf.expiry[0]
+	if time.Until(root.expiresAt) > 0 {
+		return
+	}
+	lf.deleteLocked(root.key, ReasonExpired)
+}